@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config bundles the per-file parser settings so they can be threaded through
+// a worker pool without a long parameter list.
+type Config struct {
+	Format           string
+	Split            map[string]bool
+	Events           map[string]bool
+	PositionInterval float32
+	Resume           bool
+}
+
+// resolveInputFiles expands each CLI argument into a flat list of .dem files:
+// a literal file is passed through, a directory is scanned one level deep,
+// and anything else is treated as a glob pattern.
+func resolveInputFiles(args []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var files []string
+
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		switch {
+		case err == nil && info.IsDir():
+			matches, globErr := filepath.Glob(filepath.Join(arg, "*.dem"))
+			if globErr != nil {
+				return nil, fmt.Errorf("scanning directory %q: %v", arg, globErr)
+			}
+			for _, m := range matches {
+				add(m)
+			}
+		case err == nil:
+			add(arg)
+		default:
+			matches, globErr := filepath.Glob(arg)
+			if globErr != nil {
+				return nil, fmt.Errorf("expanding glob %q: %v", arg, globErr)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no files match %q", arg)
+			}
+			for _, m := range matches {
+				add(m)
+			}
+		}
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// outputPathFor mirrors the single-file convention from before the batch
+// refactor: foo.dem -> foo_parsed_go(.json).
+func outputPathFor(inputPath string) string {
+	return strings.Replace(inputPath, ".dem", "_parsed_go", 1)
+}
+
+// completionMarkerFor returns the file whose presence means outputPath was
+// already fully processed for format, so --resume knows what to look for:
+// --format=json writes everything to outputPath+".json", while ndjson/parquet
+// stream their bulk records elsewhere and only finish by writing the
+// outputPath+"_meta.json" side file.
+func completionMarkerFor(outputPath, format string) string {
+	if format == "json" || format == "" {
+		return outputPath + ".json"
+	}
+	return outputPath + "_meta.json"
+}
+
+// FileResult is one file's outcome from the batch run.
+type FileResult struct {
+	File           string
+	Skipped        bool
+	Err            error
+	Duration       time.Duration
+	CombatLogCount int
+	ChatCount      int
+}
+
+// BatchSummary aggregates FileResults across the whole run.
+type BatchSummary struct {
+	Results              []FileResult
+	TotalFiles           int
+	Succeeded            int
+	Skipped              int
+	Failed               int
+	CumulativeCombatLogs int
+	CumulativeChat       int
+	TotalParseTime       time.Duration
+}
+
+func (s *BatchSummary) AverageParseTime() time.Duration {
+	if s.Succeeded == 0 {
+		return 0
+	}
+	return s.TotalParseTime / time.Duration(s.Succeeded)
+}
+
+func (s *BatchSummary) Print() {
+	fmt.Println("========================================")
+	fmt.Println("Batch conversion completed!")
+	fmt.Printf("Files processed: %d (ok: %d, skipped: %d, failed: %d)\n", s.TotalFiles, s.Succeeded, s.Skipped, s.Failed)
+	fmt.Printf("Average parse time: %s\n", s.AverageParseTime())
+	fmt.Printf("Cumulative combat log events: %d\n", s.CumulativeCombatLogs)
+	fmt.Printf("Cumulative chat messages: %d\n", s.CumulativeChat)
+	if s.Failed > 0 {
+		fmt.Println("Failures:")
+		for _, r := range s.Results {
+			if r.Err != nil {
+				fmt.Printf("  %s: %v\n", r.File, r.Err)
+			}
+		}
+	}
+	fmt.Println("========================================")
+}
+
+// RunBatch processes files with a pool of workers workers (clamped to at
+// least 1 and at most len(files)), each owning its own DemParser so no state
+// is shared across files.
+func RunBatch(files []string, cfg Config, workers int) BatchSummary {
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(files) {
+		workers = len(files)
+	}
+
+	jobs := make(chan string)
+	results := make(chan FileResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range jobs {
+				results <- processFile(file, cfg)
+			}
+		}()
+	}
+
+	go func() {
+		for _, f := range files {
+			jobs <- f
+		}
+		close(jobs)
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	summary := BatchSummary{TotalFiles: len(files)}
+	for result := range results {
+		summary.Results = append(summary.Results, result)
+		switch {
+		case result.Skipped:
+			summary.Skipped++
+		case result.Err != nil:
+			summary.Failed++
+			log.Printf("[%s] failed: %v", result.File, result.Err)
+		default:
+			summary.Succeeded++
+			summary.TotalParseTime += result.Duration
+			summary.CumulativeCombatLogs += result.CombatLogCount
+			summary.CumulativeChat += result.ChatCount
+		}
+	}
+
+	sort.Slice(summary.Results, func(i, j int) bool {
+		return summary.Results[i].File < summary.Results[j].File
+	})
+	return summary
+}
+
+// processFile parses a single replay with its own DemParser and, for
+// --format=json, writes the result out the same way the single-file CLI used
+// to.
+func processFile(inputPath string, cfg Config) FileResult {
+	outputPath := outputPathFor(inputPath)
+
+	if cfg.Resume {
+		if _, err := os.Stat(completionMarkerFor(outputPath, cfg.Format)); err == nil {
+			return FileResult{File: inputPath, Skipped: true}
+		}
+	}
+
+	start := time.Now()
+	parser, err := NewDemParser(outputPath, cfg.Format, cfg.Split, cfg.Events, cfg.PositionInterval)
+	if err != nil {
+		return FileResult{File: inputPath, Err: fmt.Errorf("creating parser: %v", err)}
+	}
+
+	if err := parser.Parse(inputPath); err != nil {
+		return FileResult{File: inputPath, Err: fmt.Errorf("parsing: %v", err)}
+	}
+
+	if cfg.Format == "json" {
+		if err := parser.SaveJSON(outputPath + ".json"); err != nil {
+			return FileResult{File: inputPath, Err: fmt.Errorf("saving json: %v", err)}
+		}
+	} else {
+		// ndjson/parquet only stream CombatLog/ChatMessage/EntitySnapshot
+		// through the Sink; everything else in DemOutput still needs a home.
+		if err := parser.SaveMetaJSON(outputPath + "_meta.json"); err != nil {
+			return FileResult{File: inputPath, Err: fmt.Errorf("saving meta json: %v", err)}
+		}
+	}
+
+	return FileResult{
+		File:           inputPath,
+		Duration:       time.Since(start),
+		CombatLogCount: parser.combatLogCount,
+		ChatCount:      parser.chatMessageCount,
+	}
+}
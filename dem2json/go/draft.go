@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/dotabuff/manta"
+)
+
+// maxDraftSlots covers the largest draft we need to track: Captains Draft has
+// more pick/ban slots than Captains Mode or Ranked All Pick.
+const maxDraftSlots = 30
+
+// Ordered-draft game modes, mirrored from getGameModeName's table plus
+// Captains Draft (13), which that table doesn't carry. Ranked All Pick (22)
+// deliberately isn't here — see isOrderedDraftGameMode.
+const (
+	gameModeCaptainsMode  = 2
+	gameModeCaptainsDraft = 13
+)
+
+// DraftEvent is one pick or ban in order.
+type DraftEvent struct {
+	Order    int32   `json:"order"`
+	Team     int32   `json:"team"`
+	Type     string  `json:"type"` // "pick" or "ban"
+	HeroID   int32   `json:"heroId"`
+	HeroName string  `json:"heroName,omitempty"`
+	GameTime float32 `json:"gameTime"`
+}
+
+// DraftTracker reconstructs the pick/ban phase from CDOTAGamerules draft slots,
+// sampled every tick via PacketEntities. It only emits an event the first time
+// a slot resolves to a hero, so replaying the same tick twice is a no-op.
+type DraftTracker struct {
+	seen   map[int32]bool
+	events []DraftEvent
+}
+
+func NewDraftTracker() *DraftTracker {
+	return &DraftTracker{seen: make(map[int32]bool)}
+}
+
+// isOrderedDraftGameMode reports whether gameMode exposes its pick/ban phase
+// as the ordered m_DraftSelections array this tracker reads. Captains Mode
+// and Captains Draft both work this way. Ranked All Pick's hero-ban phase
+// (added in a later patch) is NOT an ordered pick/ban sequence — each team
+// picks from a pool with a shared set of bans applied up front, so it almost
+// certainly lives under a different, unordered field (something like
+// m_vecBannedHeroes under gameRules directly) rather than m_DraftSelections.
+// We don't have a real Ranked All Pick replay to confirm that against in this
+// environment, so rather than guess a layout and silently misreport it, we
+// leave All Pick bans out of MatchInfo.Draft until that's verified.
+func isOrderedDraftGameMode(gameMode int32) bool {
+	switch gameMode {
+	case gameModeCaptainsMode, gameModeCaptainsDraft:
+		return true
+	default:
+		return false
+	}
+}
+
+// draftHeroIDFields/draftBanFields/draftTeamFields are the networked
+// field-name candidates for each piece of a draft slot, tried in order.
+// CDOTAGamerules's m_DraftSelections struct layout can't be confirmed
+// against a real Captains Mode replay in this environment, so instead of
+// hard-coding one guess that silently resolves to nothing if it's wrong, we
+// try every plausible name we're aware of and take the first that resolves.
+var (
+	draftHeroIDFields = []string{"m_iPickedHeroID", "m_HeroID", "m_iHeroID"}
+	draftBanFields    = []string{"m_bBan", "m_bBanned"}
+	draftTeamFields   = []string{"m_iTeam", "m_nTeam"}
+)
+
+// firstInt32 returns the value of the first of prefix+"."+suffixes[i] that
+// resolves on e.
+func firstInt32(e *manta.Entity, prefix string, suffixes []string) (int32, bool) {
+	for _, suffix := range suffixes {
+		if v, ok := e.GetInt32(prefix + "." + suffix); ok {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+// Process scans the draft slots on gameRules and records any that have
+// resolved to a hero since the last call. It's a no-op outside of draft game
+// modes, since all-pick/all-random etc. don't expose m_DraftSelections.
+func (t *DraftTracker) Process(gameRules *manta.Entity, gameMode int32, gameTime float32) {
+	if !isOrderedDraftGameMode(gameMode) {
+		return
+	}
+
+	for i := 0; i < maxDraftSlots; i++ {
+		slot := int32(i)
+		if t.seen[slot] {
+			continue
+		}
+		// CDOTAGamerulesProxy exposes draft state the same way it exposes
+		// m_flGameTime/m_iGameMode/m_unMatchID elsewhere in this package: under
+		// m_pGameRules, not directly on the proxy entity.
+		prefix := fmt.Sprintf("m_pGameRules.m_DraftSelections.%04d", i)
+
+		heroID, ok := firstInt32(gameRules, prefix, draftHeroIDFields)
+		if !ok || heroID <= 0 {
+			continue // 这一位还没确定
+		}
+
+		banFlag, _ := firstInt32(gameRules, prefix, draftBanFields)
+		team, _ := firstInt32(gameRules, prefix, draftTeamFields)
+
+		eventType := "pick"
+		if banFlag != 0 {
+			eventType = "ban"
+		}
+
+		t.seen[slot] = true
+		t.events = append(t.events, DraftEvent{
+			Order:    int32(len(t.events)),
+			Team:     team,
+			Type:     eventType,
+			HeroID:   heroID,
+			HeroName: HeroNameByID(heroID),
+			GameTime: gameTime,
+		})
+	}
+}
+
+// Events returns the draft in the order it was resolved.
+func (t *DraftTracker) Events() []DraftEvent {
+	return t.events
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dotabuff/manta"
+)
+
+// MatchEvents holds the high-signal game events decoded via parser.OnGameEvent,
+// as opposed to the lower-level PacketEntities/CombatLogEntry callbacks.
+type MatchEvents struct {
+	PlayerKills      []PlayerKillEvent   `json:"playerKills,omitempty"`
+	ItemPurchases    []ItemPurchaseEvent `json:"itemPurchases,omitempty"`
+	HeroLevelUps     []HeroLevelUp       `json:"heroLevelUps,omitempty"`
+	TowerKills       []TowerKill         `json:"towerKills,omitempty"`
+	RoshanKills      []RoshanKill        `json:"roshanKills,omitempty"`
+	HeroPicks        []HeroPick          `json:"heroPicks,omitempty"`
+	GameStateChanges []GameStateChange   `json:"gameStateChanges,omitempty"`
+	EntitiesKilled   []EntityKilled      `json:"entitiesKilled,omitempty"`
+	ChatWheelEvents  []ChatWheelEvent    `json:"chatWheelEvents,omitempty"`
+}
+
+type PlayerKillEvent struct {
+	Tick           uint32  `json:"tick"`
+	GameTime       float32 `json:"gameTime"`
+	KillerPlayerID int32   `json:"killerPlayerId"`
+	VictimPlayerID int32   `json:"victimPlayerId"`
+	KillerHero     string  `json:"killerHero,omitempty"`
+	VictimHero     string  `json:"victimHero,omitempty"`
+}
+
+type ItemPurchaseEvent struct {
+	Tick     uint32  `json:"tick"`
+	GameTime float32 `json:"gameTime"`
+	PlayerID int32   `json:"playerId"`
+	ItemName string  `json:"itemName"`
+}
+
+type HeroLevelUp struct {
+	Tick     uint32  `json:"tick"`
+	GameTime float32 `json:"gameTime"`
+	PlayerID int32   `json:"playerId"`
+	Level    int32   `json:"level"`
+}
+
+type TowerKill struct {
+	Tick       uint32  `json:"tick"`
+	GameTime   float32 `json:"gameTime"`
+	KillerHero string  `json:"killerHero,omitempty"`
+	Team       int32   `json:"team"`
+	Tier       int32   `json:"tier"`
+	Lane       string  `json:"lane,omitempty"`
+}
+
+type RoshanKill struct {
+	Tick        uint32  `json:"tick"`
+	GameTime    float32 `json:"gameTime"`
+	KillerTeam  int32   `json:"killerTeam"`
+	AegisPickup bool    `json:"aegisPickup"`
+}
+
+type HeroPick struct {
+	Tick     uint32  `json:"tick"`
+	GameTime float32 `json:"gameTime"`
+	PlayerID int32   `json:"playerId"`
+	HeroName string  `json:"heroName,omitempty"`
+	Team     int32   `json:"team"`
+}
+
+type GameStateChange struct {
+	Tick     uint32  `json:"tick"`
+	GameTime float32 `json:"gameTime"`
+	NewState int32   `json:"newState"`
+}
+
+type EntityKilled struct {
+	Tick       uint32  `json:"tick"`
+	GameTime   float32 `json:"gameTime"`
+	KillerName string  `json:"killerName,omitempty"`
+	VictimName string  `json:"victimName,omitempty"`
+}
+
+type ChatWheelEvent struct {
+	Tick      uint32  `json:"tick"`
+	GameTime  float32 `json:"gameTime"`
+	PlayerID  int32   `json:"playerId"`
+	MessageID int32   `json:"messageId"`
+}
+
+// allGameEvents is the set of event names registerGameEventCallbacks knows how
+// to decode, keyed by the short name used in --events.
+var allGameEvents = []string{
+	"player_kill",
+	"item_purchased",
+	"hero_levelup",
+	"tower_kill",
+	"roshan_kill",
+	"player_pick_hero",
+	"game_rules_state_change",
+	"entity_killed",
+	"chat_event",
+}
+
+// registerGameEventCallbacks wires parser.OnGameEvent for each event in
+// allGameEvents that's present in filter (or every one of them, if filter is
+// empty). Events land in dp.output.Events instead of the per-callback
+// DemOutput sections used for chat/combat log, since there's no volume
+// pressure that calls for streaming them through the Sink.
+func (dp *DemParser) registerGameEventCallbacks(filter map[string]bool) {
+	p := dp.parser
+	dp.output.Events = &MatchEvents{}
+	enabled := func(name string) bool {
+		return len(filter) == 0 || filter[name]
+	}
+
+	if enabled("player_kill") {
+		p.OnGameEvent("dota_player_kill", func(e *manta.GameEvent) error {
+			killerID, _ := e.GetInt32("killer_player_id")
+			victimID, _ := e.GetInt32("player_id")
+			event := PlayerKillEvent{
+				Tick:           p.Tick,
+				GameTime:       dp.gameTime,
+				KillerPlayerID: killerID,
+				VictimPlayerID: victimID,
+				KillerHero:     dp.heroNameForPlayer(killerID),
+				VictimHero:     dp.heroNameForPlayer(victimID),
+			}
+			dp.output.Events.PlayerKills = append(dp.output.Events.PlayerKills, event)
+			return nil
+		})
+	}
+
+	if enabled("item_purchased") {
+		p.OnGameEvent("dota_item_purchased", func(e *manta.GameEvent) error {
+			playerID, _ := e.GetInt32("player_id")
+			itemName, _ := e.GetString("item_name")
+			dp.output.Events.ItemPurchases = append(dp.output.Events.ItemPurchases, ItemPurchaseEvent{
+				Tick:     p.Tick,
+				GameTime: dp.gameTime,
+				PlayerID: playerID,
+				ItemName: itemName,
+			})
+			return nil
+		})
+	}
+
+	if enabled("hero_levelup") {
+		p.OnGameEvent("dota_hero_levelup", func(e *manta.GameEvent) error {
+			playerID, _ := e.GetInt32("player_id")
+			level, _ := e.GetInt32("level")
+			dp.output.Events.HeroLevelUps = append(dp.output.Events.HeroLevelUps, HeroLevelUp{
+				Tick:     p.Tick,
+				GameTime: dp.gameTime,
+				PlayerID: playerID,
+				Level:    level,
+			})
+			dp.stats.OnHeroLevelUp(playerID, level, p.Tick, dp.gameTime)
+			return nil
+		})
+	}
+
+	if enabled("tower_kill") {
+		p.OnGameEvent("dota_tower_kill", func(e *manta.GameEvent) error {
+			killerIdx, _ := e.GetInt32("entindex_killer")
+			towerIdx, _ := e.GetInt32("entindex_killed")
+			team, tier, lane := dp.towerInfoForEntityIndex(towerIdx)
+			dp.output.Events.TowerKills = append(dp.output.Events.TowerKills, TowerKill{
+				Tick:       p.Tick,
+				GameTime:   dp.gameTime,
+				KillerHero: dp.heroNameForEntityIndex(killerIdx),
+				Team:       team,
+				Tier:       tier,
+				Lane:       lane,
+			})
+			return nil
+		})
+	}
+
+	if enabled("roshan_kill") {
+		p.OnGameEvent("dota_roshan_kill", func(e *manta.GameEvent) error {
+			team, _ := e.GetInt32("team_number")
+			_, aegisErr := e.GetInt32("aegis_entindex")
+			hasAegis := aegisErr == nil
+			dp.output.Events.RoshanKills = append(dp.output.Events.RoshanKills, RoshanKill{
+				Tick:        p.Tick,
+				GameTime:    dp.gameTime,
+				KillerTeam:  team,
+				AegisPickup: hasAegis,
+			})
+			return nil
+		})
+	}
+
+	if enabled("player_pick_hero") {
+		p.OnGameEvent("dota_player_pick_hero", func(e *manta.GameEvent) error {
+			playerID, _ := e.GetInt32("player_id")
+			heroName, _ := e.GetString("hero")
+			team, _ := e.GetInt32("team")
+			dp.output.Events.HeroPicks = append(dp.output.Events.HeroPicks, HeroPick{
+				Tick:     p.Tick,
+				GameTime: dp.gameTime,
+				PlayerID: playerID,
+				HeroName: strings.TrimPrefix(heroName, "npc_dota_hero_"),
+				Team:     team,
+			})
+			return nil
+		})
+	}
+
+	if enabled("game_rules_state_change") {
+		p.OnGameEvent("game_rules_state_change", func(e *manta.GameEvent) error {
+			newState, _ := e.GetInt32("new_state")
+			dp.output.Events.GameStateChanges = append(dp.output.Events.GameStateChanges, GameStateChange{
+				Tick:     p.Tick,
+				GameTime: dp.gameTime,
+				NewState: newState,
+			})
+			return nil
+		})
+	}
+
+	if enabled("entity_killed") {
+		p.OnGameEvent("entity_killed", func(e *manta.GameEvent) error {
+			killerIdx, _ := e.GetInt32("entindex_killer")
+			victimIdx, _ := e.GetInt32("entindex_killed")
+			dp.output.Events.EntitiesKilled = append(dp.output.Events.EntitiesKilled, EntityKilled{
+				Tick:       p.Tick,
+				GameTime:   dp.gameTime,
+				KillerName: dp.entityClassNameForIndex(killerIdx),
+				VictimName: dp.entityClassNameForIndex(victimIdx),
+			})
+			return nil
+		})
+	}
+
+	if enabled("chat_event") {
+		p.OnGameEvent("dota_chat_event", func(e *manta.GameEvent) error {
+			playerID, _ := e.GetInt32("playerid")
+			messageID, _ := e.GetInt32("type")
+			dp.output.Events.ChatWheelEvents = append(dp.output.Events.ChatWheelEvents, ChatWheelEvent{
+				Tick:      p.Tick,
+				GameTime:  dp.gameTime,
+				PlayerID:  playerID,
+				MessageID: messageID,
+			})
+			return nil
+		})
+	}
+}
+
+// heroNameForPlayer looks up the hero currently attached to playerID, falling
+// back to "" if the player resource hasn't resolved it yet.
+func (dp *DemParser) heroNameForPlayer(playerID int32) string {
+	if player, ok := dp.playerMap[playerID]; ok {
+		return player.HeroName
+	}
+	return ""
+}
+
+// entityClassNameForIndex resolves an entindex to its class name, e.g. for
+// events that identify the killer/victim by entity index rather than player
+// slot.
+func (dp *DemParser) entityClassNameForIndex(index int32) string {
+	entity := dp.parser.FindEntity(index)
+	if entity == nil {
+		return ""
+	}
+	return entity.GetClassName()
+}
+
+// heroNameForEntityIndex resolves an entindex to a hero name, stripping the
+// "CDOTA_Unit_Hero_" class prefix the same way registerCallbacks does when
+// building playerMap.
+func (dp *DemParser) heroNameForEntityIndex(index int32) string {
+	return strings.TrimPrefix(dp.entityClassNameForIndex(index), "CDOTA_Unit_Hero_")
+}
+
+// towerInfoForEntityIndex derives team/tier/lane from a tower's entity name,
+// since dota_tower_kill doesn't carry those as separate event fields. Every
+// tower shares one networked class (CDOTA_BaseNPC_Tower), so the class name
+// can't tell them apart — only the per-instance entity name can (e.g.
+// "dota_goodguys_tower1_mid", "dota_badguys_tower3_top").
+//
+// TODO(towers): m_iName is our best guess at where the per-instance name
+// lives on the networked entity and hasn't been checked against a real
+// replay; verify before relying on Team/Tier/Lane downstream.
+func (dp *DemParser) towerInfoForEntityIndex(index int32) (team int32, tier int32, lane string) {
+	entity := dp.parser.FindEntity(index)
+	if entity == nil {
+		return 0, 0, ""
+	}
+	name, ok := entity.GetString("m_iName")
+	if !ok || name == "" {
+		return 0, 0, ""
+	}
+	return parseTowerName(name)
+}
+
+// parseTowerName pulls team/tier/lane out of a tower's entity name. Split out
+// from towerInfoForEntityIndex so the parsing itself can be tested without a
+// live manta.Entity.
+func parseTowerName(name string) (team int32, tier int32, lane string) {
+	name = strings.ToLower(name)
+
+	switch {
+	case strings.Contains(name, "goodguys"):
+		team = teamGoodguys
+	case strings.Contains(name, "badguys"):
+		team = teamBadguys
+	}
+
+	switch {
+	case strings.Contains(name, "_top"):
+		lane = "top"
+	case strings.Contains(name, "_mid"):
+		lane = "mid"
+	case strings.Contains(name, "_bot"):
+		lane = "bot"
+	}
+
+	for t := int32(1); t <= 4; t++ {
+		if strings.Contains(name, fmt.Sprintf("tower%d", t)) {
+			tier = t
+			break
+		}
+	}
+	return team, tier, lane
+}
+
+// Team IDs, mirrored from the DOTA_TEAM_* constants dump_api.go enumerates.
+const (
+	teamGoodguys = 2
+	teamBadguys  = 3
+)
@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+// TestParseTowerName covers the real entity-name tokens Source 2 uses for
+// towers (tier 4 is the base/"melee" tower each lane funnels into).
+func TestParseTowerName(t *testing.T) {
+	cases := []struct {
+		name string
+		team int32
+		tier int32
+		lane string
+	}{
+		{"dota_goodguys_tower1_mid", teamGoodguys, 1, "mid"},
+		{"dota_badguys_tower2_top", teamBadguys, 2, "top"},
+		{"dota_goodguys_tower3_bot", teamGoodguys, 3, "bot"},
+		{"dota_badguys_tower4_top", teamBadguys, 4, "top"},
+	}
+
+	for _, c := range cases {
+		team, tier, lane := parseTowerName(c.name)
+		if team != c.team || tier != c.tier || lane != c.lane {
+			t.Errorf("parseTowerName(%q) = (%d, %d, %q), want (%d, %d, %q)",
+				c.name, team, tier, lane, c.team, c.tier, c.lane)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bufio"
+	"compress/bzip2"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/dotabuff/manta"
+)
+
+// resumableHTTPReader is an io.Reader over an HTTP GET that re-issues the
+// request with a Range header (validated against the original ETag/
+// Last-Modified via If-Range) whenever the underlying connection drops, so a
+// multi-GB replay download doesn't have to restart from byte zero.
+type resumableHTTPReader struct {
+	url        string
+	client     *http.Client
+	offset     int64
+	etag       string
+	lastMod    string
+	resp       *http.Response
+	maxRetries int
+}
+
+func newResumableHTTPReader(url string) *resumableHTTPReader {
+	return &resumableHTTPReader{
+		url:        url,
+		client:     &http.Client{},
+		maxRetries: 5,
+	}
+}
+
+func (r *resumableHTTPReader) connect() error {
+	req, err := http.NewRequest(http.MethodGet, r.url, nil)
+	if err != nil {
+		return err
+	}
+	if r.offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", r.offset))
+		switch {
+		case r.etag != "":
+			req.Header.Set("If-Range", r.etag)
+		case r.lastMod != "":
+			req.Header.Set("If-Range", r.lastMod)
+		}
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		return fmt.Errorf("unexpected status %d fetching %s", resp.StatusCode, r.url)
+	}
+	if r.offset > 0 && resp.StatusCode != http.StatusPartialContent {
+		// If-Range failed: the server sent the whole body from byte zero.
+		resp.Body.Close()
+		return fmt.Errorf("server did not resume %s at offset %d (status %d)", r.url, r.offset, resp.StatusCode)
+	}
+
+	if r.etag == "" {
+		r.etag = resp.Header.Get("ETag")
+	}
+	if r.lastMod == "" {
+		r.lastMod = resp.Header.Get("Last-Modified")
+	}
+	r.resp = resp
+	return nil
+}
+
+// Read satisfies io.Reader. On a dropped connection it transparently
+// reconnects at the last known offset instead of surfacing the error,
+// retrying up to maxRetries times with a short backoff.
+func (r *resumableHTTPReader) Read(p []byte) (int, error) {
+	if r.resp == nil {
+		if err := r.connect(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.resp.Body.Read(p)
+	r.offset += int64(n)
+	if err == nil || err == io.EOF {
+		return n, err
+	}
+
+	for attempt := 0; attempt < r.maxRetries; attempt++ {
+		r.resp.Body.Close()
+		r.resp = nil
+		time.Sleep(time.Duration(attempt+1) * time.Second)
+		if connErr := r.connect(); connErr == nil {
+			return n, nil
+		}
+	}
+	return n, fmt.Errorf("connection dropped at offset %d and all retries failed: %v", r.offset, err)
+}
+
+func (r *resumableHTTPReader) Close() error {
+	if r.resp != nil {
+		return r.resp.Body.Close()
+	}
+	return nil
+}
+
+// ParseURL streams a .dem (optionally .dem.bz2) replay straight from an HTTP
+// URL into the manta parser, rather than requiring it be downloaded to disk
+// first. The first 16 bytes of the decompressed demo header are recorded in
+// DemOutput.HeaderChecksum so a truncated fetch can be detected downstream.
+func (dp *DemParser) ParseURL(url string) error {
+	dp.output.SourceFile = path.Base(url)
+
+	httpReader := newResumableHTTPReader(url)
+	defer httpReader.Close()
+
+	var reader io.Reader = httpReader
+	if strings.HasSuffix(strings.ToLower(url), ".bz2") {
+		reader = bzip2.NewReader(httpReader)
+	}
+	buffered := bufio.NewReader(reader)
+
+	header, err := buffered.Peek(16)
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("unable to read demo header: %v", err)
+	}
+	dp.output.HeaderChecksum = fmt.Sprintf("%x", header)
+
+	parser, err := manta.NewStreamParser(buffered)
+	if err != nil {
+		return fmt.Errorf("unable to create parser: %v", err)
+	}
+	dp.parser = parser
+
+	dp.registerCallbacks()
+	dp.registerGameEventCallbacks(dp.eventFilter)
+
+	fmt.Printf("Starting streamed parse from %s...\n", url)
+	startTime := time.Now()
+
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Printf("Recovered from a panic during streamed parsing: %v\n", r)
+			fmt.Println("Parsing will stop, but collected data will be saved.")
+		}
+	}()
+
+	if err := parser.Start(); err != nil {
+		fmt.Printf("Parser stopped with message: %v\n", err)
+	}
+
+	fmt.Printf("Streamed parsing finished in %.2f seconds\n", time.Since(startTime).Seconds())
+
+	dp.finalize()
+	return nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import "fmt"
+
+// heroNameByID maps Dota 2 hero IDs to their internal (non-localized) hero
+// name, the same spelling used after stripping "npc_dota_hero_" off the hero
+// string of a dota_player_pick_hero event (see events.go). There's no `dota` package enum to resolve this from — hero IDs
+// aren't part of the networked protobuf schema, the same reason
+// dump_api.go's collectDotaConstants hand-enumerates its constants instead of
+// reflecting them off the package. This is hand-maintained rather than
+// generated from the game files, so it only covers the original hero pool —
+// extend it as draft/position exports surface gaps for newer heroes.
+var heroNameByID = map[int32]string{
+	1:   "antimage",
+	2:   "axe",
+	3:   "bane",
+	4:   "bloodseeker",
+	5:   "crystal_maiden",
+	6:   "drow_ranger",
+	7:   "earthshaker",
+	8:   "juggernaut",
+	9:   "mirana",
+	10:  "morphling",
+	11:  "nevermore",
+	12:  "phantom_lancer",
+	13:  "puck",
+	14:  "pudge",
+	15:  "razor",
+	16:  "sand_king",
+	17:  "storm_spirit",
+	18:  "sven",
+	19:  "tiny",
+	20:  "vengefulspirit",
+	21:  "windrunner",
+	22:  "zuus",
+	23:  "kunkka",
+	25:  "lina",
+	26:  "lion",
+	27:  "shadow_shaman",
+	28:  "slardar",
+	29:  "tidehunter",
+	30:  "witch_doctor",
+	31:  "lich",
+	32:  "riki",
+	33:  "enigma",
+	34:  "tinker",
+	35:  "sniper",
+	36:  "necrolyte",
+	37:  "warlock",
+	38:  "beastmaster",
+	39:  "queenofpain",
+	40:  "venomancer",
+	41:  "faceless_void",
+	42:  "skeleton_king",
+	43:  "death_prophet",
+	44:  "phantom_assassin",
+	45:  "pugna",
+	46:  "templar_assassin",
+	47:  "viper",
+	48:  "luna",
+	49:  "dragon_knight",
+	50:  "dazzle",
+	51:  "rattletrap",
+	52:  "leshrac",
+	53:  "furion",
+	54:  "lifestealer",
+	55:  "dark_seer",
+	56:  "clinkz",
+	57:  "omniknight",
+	58:  "enchantress",
+	59:  "huskar",
+	60:  "nyx_assassin",
+	61:  "night_stalker",
+	62:  "skywrath_mage",
+	63:  "magnataur",
+	64:  "centaur",
+	65:  "doom_bringer",
+	66:  "ancient_apparition",
+	67:  "spectre",
+	74:  "invoker",
+	86:  "rubick",
+	100: "disruptor",
+	106: "ember_spirit",
+	109: "terrorblade",
+	110: "phoenix",
+}
+
+// HeroNameByID resolves a hero ID to its internal name, falling back to a
+// placeholder so downstream consumers always get a non-empty string even for
+// IDs not yet in the table above.
+func HeroNameByID(heroID int32) string {
+	if name, ok := heroNameByID[heroID]; ok {
+		return name
+	}
+	return fmt.Sprintf("hero_%d", heroID)
+}
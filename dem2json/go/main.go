@@ -2,10 +2,13 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"time"
 
@@ -13,23 +16,27 @@ import (
 	"github.com/dotabuff/manta/dota"
 )
 
-// ... (所有 struct 定义和 NewDemParser 等函数保持不变) ...
 type DemOutput struct {
-	MatchInfo    MatchInfo     `json:"matchInfo"`
-	Players      []Player      `json:"players"`
-	ChatMessages []ChatMessage `json:"chatMessages"`
-	CombatLogs   []CombatLog   `json:"combatLogs"`
-	Statistics   Statistics    `json:"statistics"`
-	SourceFile   string        `json:"sourceFile"`
-	ProcessedAt  int64         `json:"processedAt"`
+	MatchInfo       MatchInfo        `json:"matchInfo"`
+	Players         []Player         `json:"players"`
+	ChatMessages    []ChatMessage    `json:"chatMessages"`
+	CombatLogs      []CombatLog      `json:"combatLogs"`
+	EntitySnapshots []EntitySnapshot `json:"entitySnapshots,omitempty"`
+	Events          *MatchEvents     `json:"events,omitempty"`
+	Positions       *PositionData    `json:"positions,omitempty"`
+	Statistics      Statistics       `json:"statistics"`
+	SourceFile      string           `json:"sourceFile"`
+	HeaderChecksum  string           `json:"headerChecksum,omitempty"`
+	ProcessedAt     int64            `json:"processedAt"`
 }
 type MatchInfo struct {
-	GameTime     float32 `json:"gameTime"`
-	GameMode     int32   `json:"gameMode"`
-	GameModeName string  `json:"gameModeName"`
-	MatchID      uint64  `json:"matchId,omitempty"`
-	Winner       string  `json:"winner,omitempty"`
-	Duration     float32 `json:"duration"`
+	GameTime     float32      `json:"gameTime"`
+	GameMode     int32        `json:"gameMode"`
+	GameModeName string       `json:"gameModeName"`
+	MatchID      uint64       `json:"matchId,omitempty"`
+	Winner       string       `json:"winner,omitempty"`
+	Duration     float32      `json:"duration"`
+	Draft        []DraftEvent `json:"draft,omitempty"`
 }
 type Player struct {
 	PlayerID   int32  `json:"playerId"`
@@ -39,42 +46,72 @@ type Player struct {
 	Team       int32  `json:"team"`
 }
 type ChatMessage struct {
-	Tick       uint32  `json:"tick"`
-	GameTime   float32 `json:"gameTime,omitempty"`
-	PlayerName string  `json:"playerName,omitempty"`
-	Message    string  `json:"message,omitempty"`
+	Tick       uint32  `json:"tick" parquet:"name=tick, type=INT64"`
+	GameTime   float32 `json:"gameTime,omitempty" parquet:"name=game_time, type=FLOAT"`
+	PlayerName string  `json:"playerName,omitempty" parquet:"name=player_name, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Message    string  `json:"message,omitempty" parquet:"name=message, type=BYTE_ARRAY, convertedtype=UTF8"`
 }
 type CombatLog struct {
-	Tick       uint32  `json:"tick"`
-	GameTime   float32 `json:"gameTime,omitempty"`
-	Type       string  `json:"type"`
-	SourceName string  `json:"sourceName,omitempty"`
-	TargetName string  `json:"targetName,omitempty"`
-	Value      uint32  `json:"value,omitempty"`
+	Tick       uint32  `json:"tick" parquet:"name=tick, type=INT64"`
+	GameTime   float32 `json:"gameTime,omitempty" parquet:"name=game_time, type=FLOAT"`
+	Type       string  `json:"type" parquet:"name=type, type=BYTE_ARRAY, convertedtype=UTF8"`
+	SourceName string  `json:"sourceName,omitempty" parquet:"name=source_name, type=BYTE_ARRAY, convertedtype=UTF8"`
+	TargetName string  `json:"targetName,omitempty" parquet:"name=target_name, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Value      uint32  `json:"value,omitempty" parquet:"name=value, type=INT64"`
 }
 type Statistics struct {
-	TotalTicks       uint32  `json:"totalTicks"`
-	Duration         float32 `json:"duration"`
-	CombatLogCount   int     `json:"combatLogCount"`
-	ChatMessageCount int     `json:"chatMessageCount"`
+	TotalTicks       uint32              `json:"totalTicks"`
+	Duration         float32             `json:"duration"`
+	CombatLogCount   int                 `json:"combatLogCount"`
+	ChatMessageCount int                 `json:"chatMessageCount"`
+	Players          []*PlayerMatchStats `json:"players,omitempty"`
 }
 type DemParser struct {
 	parser    *manta.Parser
 	output    *DemOutput
 	playerMap map[int32]*Player
 	gameTime  float32
+
+	// sink 接收增量记录（combat log / chat / entity snapshot），
+	// 默认是把记录塞回 output 切片的 bufferSink，这样 --format=json 的行为不变。
+	sink Sink
+
+	chatMessageCount int
+	combatLogCount   int
+
+	stats     *PlayerStatsTracker
+	draft     *DraftTracker
+	positions *PositionTracker // nil unless --positions was passed
+
+	eventFilter map[string]bool
 }
 
-func NewDemParser() *DemParser {
-	return &DemParser{
+// NewDemParser 创建一个 parser。format 决定记录如何被持久化：
+// "json"（默认）继续缓存到 DemOutput 供 SaveJSON 使用；"ndjson"/"parquet"
+// 会在回调里增量落盘，避免把整场比赛缓存在内存中。
+func NewDemParser(outputPath string, format string, split map[string]bool, eventFilter map[string]bool, positionInterval float32) (*DemParser, error) {
+	dp := &DemParser{
 		output: &DemOutput{
 			Players:      make([]Player, 0),
 			ChatMessages: make([]ChatMessage, 0),
 			CombatLogs:   make([]CombatLog, 0),
 			ProcessedAt:  time.Now().Unix(),
 		},
-		playerMap: make(map[int32]*Player),
+		playerMap:   make(map[int32]*Player),
+		eventFilter: eventFilter,
 	}
+	dp.stats = NewPlayerStatsTracker()
+	dp.draft = NewDraftTracker()
+	if positionInterval > 0 {
+		dp.positions = NewPositionTracker(positionInterval)
+	}
+
+	sink, err := newSink(outputPath, format, split, dp.output)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create sink: %v", err)
+	}
+	dp.sink = sink
+	return dp, nil
 }
 
 func (dp *DemParser) Parse(filename string) error {
@@ -92,6 +129,7 @@ func (dp *DemParser) Parse(filename string) error {
 	dp.parser = parser
 
 	dp.registerCallbacks()
+	dp.registerGameEventCallbacks(dp.eventFilter)
 
 	fmt.Println("Starting parse...")
 	startTime := time.Now()
@@ -130,7 +168,10 @@ func (dp *DemParser) registerCallbacks() {
 			PlayerName: m.GetParam1(),
 			Message:    m.GetParam2(),
 		}
-		dp.output.ChatMessages = append(dp.output.ChatMessages, chat)
+		dp.chatMessageCount++
+		if err := dp.sink.WriteChatMessage(chat); err != nil {
+			log.Printf("sink: failed to write chat message: %v", err)
+		}
 		return nil
 	})
 
@@ -142,7 +183,7 @@ func (dp *DemParser) registerCallbacks() {
 		sourceName, _ := p.LookupStringByIndex("CombatLogNames", int32(m.GetAttackerName()))
 		targetName, _ := p.LookupStringByIndex("CombatLogNames", int32(m.GetTargetName()))
 
-		log := CombatLog{
+		entry := CombatLog{
 			Tick:       p.Tick,
 			GameTime:   dp.gameTime,
 			Type:       getCombatLogTypeName(m.GetType()),
@@ -150,7 +191,11 @@ func (dp *DemParser) registerCallbacks() {
 			TargetName: targetName,
 			Value:      m.GetValue(),
 		}
-		dp.output.CombatLogs = append(dp.output.CombatLogs, log)
+		dp.combatLogCount++
+		dp.stats.Process(entry)
+		if err := dp.sink.WriteCombatLog(entry); err != nil {
+			log.Printf("sink: failed to write combat log entry: %v", err)
+		}
 		return nil
 	})
 
@@ -178,13 +223,14 @@ func (dp *DemParser) registerCallbacks() {
 			if matchID, ok := gameRules.GetUint64("m_pGameRules.m_unMatchID"); ok {
 				dp.output.MatchInfo.MatchID = matchID
 			}
+			dp.draft.Process(gameRules, dp.output.MatchInfo.GameMode, dp.gameTime)
 		}
 
 		// 查找玩家资源实体
 		playerResourceEntities := p.FilterEntity(func(e *manta.Entity) bool {
 			return e != nil && e.GetClassName() == "CDOTA_PlayerResource"
 		})
-		
+
 		// 关键安全检查: 确保我们真的找到了实体
 		if len(playerResourceEntities) > 0 {
 			playerResource := playerResourceEntities[0]
@@ -212,10 +258,33 @@ func (dp *DemParser) registerCallbacks() {
 							HeroName:   heroName,
 							Team:       teamVal,
 						}
+						dp.stats.RegisterPlayer(playerID, heroName)
+
+						// 第一次解析出英雄实体时落一条快照，供下游按 entindex 还原
+						// 阵容/出生点之类的上下文用；持续每 tick 快照全部实体太重了。
+						if heroEntity != nil {
+							snapshot := EntitySnapshot{
+								Tick:      p.Tick,
+								GameTime:  dp.gameTime,
+								ClassName: heroEntity.GetClassName(),
+								Properties: map[string]string{
+									"playerId": fmt.Sprintf("%d", playerID),
+									"heroName": heroName,
+									"team":     fmt.Sprintf("%d", teamVal),
+								},
+							}
+							if err := dp.sink.WriteEntitySnapshot(snapshot); err != nil {
+								log.Printf("sink: failed to write entity snapshot: %v", err)
+							}
+						}
 					}
 				}
 			}
 		}
+
+		if dp.positions != nil {
+			dp.positions.Process(p, dp.playerMap, dp.gameTime)
+		}
 		return nil
 	})
 
@@ -236,10 +305,20 @@ func (dp *DemParser) finalize() {
 		dp.output.Statistics.TotalTicks = dp.parser.Tick
 	}
 	dp.output.Statistics.Duration = dp.gameTime
-	dp.output.Statistics.ChatMessageCount = len(dp.output.ChatMessages)
-	dp.output.Statistics.CombatLogCount = len(dp.output.CombatLogs)
+	// 用计数器而不是 len(slice)，因为流式 sink 不再往 output 里塞记录。
+	dp.output.Statistics.ChatMessageCount = dp.chatMessageCount
+	dp.output.Statistics.CombatLogCount = dp.combatLogCount
 	dp.output.MatchInfo.GameModeName = getGameModeName(dp.output.MatchInfo.GameMode)
 	dp.output.MatchInfo.GameTime = dp.gameTime
+	dp.output.Statistics.Players = dp.stats.Finalize(dp.gameTime)
+	dp.output.MatchInfo.Draft = dp.draft.Events()
+	if dp.positions != nil {
+		dp.output.Positions = dp.positions.Data()
+	}
+
+	if err := dp.sink.Close(); err != nil {
+		log.Printf("sink: error closing: %v", err)
+	}
 }
 
 func (dp *DemParser) SaveJSON(outputPath string) error {
@@ -257,35 +336,122 @@ func (dp *DemParser) SaveJSON(outputPath string) error {
 	return nil
 }
 
+// metaOutput holds everything in DemOutput except the three record kinds the
+// Sink already streams (CombatLogs, ChatMessages, EntitySnapshots). It's what
+// SaveMetaJSON writes out for --format=ndjson/parquet, since those modes
+// never populate the sink-owned slices and would otherwise silently drop
+// MatchInfo/Players/Events/Positions/Statistics on the floor.
+type metaOutput struct {
+	MatchInfo      MatchInfo     `json:"matchInfo"`
+	Players        []Player      `json:"players"`
+	Events         *MatchEvents  `json:"events,omitempty"`
+	Positions      *PositionData `json:"positions,omitempty"`
+	Statistics     Statistics    `json:"statistics"`
+	SourceFile     string        `json:"sourceFile"`
+	HeaderChecksum string        `json:"headerChecksum,omitempty"`
+	ProcessedAt    int64         `json:"processedAt"`
+}
+
+// SaveMetaJSON writes the non-streamed sections of DemOutput. Callers using
+// --format=json should keep calling SaveJSON instead, which already includes
+// everything metaOutput does plus the full CombatLogs/ChatMessages slices.
+func (dp *DemParser) SaveMetaJSON(outputPath string) error {
+	meta := metaOutput{
+		MatchInfo:      dp.output.MatchInfo,
+		Players:        dp.output.Players,
+		Events:         dp.output.Events,
+		Positions:      dp.output.Positions,
+		Statistics:     dp.output.Statistics,
+		SourceFile:     dp.output.SourceFile,
+		HeaderChecksum: dp.output.HeaderChecksum,
+		ProcessedAt:    dp.output.ProcessedAt,
+	}
+	jsonData, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling meta JSON: %v", err)
+	}
+	if err := os.WriteFile(outputPath, jsonData, 0644); err != nil {
+		return fmt.Errorf("error writing file: %v", err)
+	}
+	fmt.Printf("Meta JSON saved to: %s (%.2f KB)\n", outputPath, float64(len(jsonData))/1024)
+	return nil
+}
+
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Println("Usage: go run main.go <input.dem>")
-		os.Exit(1)
+	format := flag.String("format", "json", "output format: json|ndjson|parquet")
+	split := flag.String("split", "", "comma-separated record types to route to their own file (combatlog,chat,entities); only applies to ndjson (parquet always writes one file per record type)")
+	events := flag.String("events", "", "comma-separated game events to subscribe to (default: all of "+strings.Join(allGameEvents, ",")+")")
+	positions := flag.Bool("positions", false, "sample hero positions for heatmap generation")
+	positionInterval := flag.Float64("position-interval", defaultPositionSampleInterval, "game-time seconds between position samples; only applies with --positions")
+	workers := flag.Int("workers", runtime.NumCPU(), "number of .dem files to process concurrently")
+	resume := flag.Bool("resume", false, "skip files whose _parsed_go output already exists")
+	url := flag.String("url", "", "fetch and parse a .dem/.dem.bz2 replay from this URL instead of local files")
+	flag.Parse()
+
+	var posInterval float32
+	if *positions {
+		posInterval = float32(*positionInterval)
+	}
+	cfg := Config{
+		Format:           *format,
+		Split:            toSet(*split),
+		Events:           toSet(*events),
+		PositionInterval: posInterval,
+		Resume:           *resume,
 	}
-	inputPath := os.Args[1]
-	outputPath := strings.Replace(inputPath, ".dem", "_parsed_go.json", 1)
 
-	fmt.Println("========================================")
-	fmt.Println("Go Manta DEM to JSON Converter")
-	fmt.Println("========================================")
+	if *url != "" {
+		parser, err := NewDemParser(outputPathFor(path.Base(*url)), cfg.Format, cfg.Split, cfg.Events, cfg.PositionInterval)
+		if err != nil {
+			log.Fatalf("Error creating parser: %v", err)
+		}
+		if err := parser.ParseURL(*url); err != nil {
+			log.Fatalf("Error streaming replay: %v", err)
+		}
+		if cfg.Format == "json" {
+			if err := parser.SaveJSON(outputPathFor(path.Base(*url)) + ".json"); err != nil {
+				log.Fatalf("Error saving JSON: %v", err)
+			}
+		} else {
+			if err := parser.SaveMetaJSON(outputPathFor(path.Base(*url)) + "_meta.json"); err != nil {
+				log.Fatalf("Error saving meta JSON: %v", err)
+			}
+		}
+		fmt.Printf("Header checksum: %s\n", parser.output.HeaderChecksum)
+		return
+	}
 
-	parser := NewDemParser()
-	err := parser.Parse(inputPath)
-	if err != nil {
-		log.Printf("Error during parsing: %v\n", err)
+	if flag.NArg() < 1 {
+		fmt.Println("Usage: go run main.go [flags] <input.dem | dir | glob> [more inputs...]")
+		os.Exit(1)
 	}
 
-	err = parser.SaveJSON(outputPath)
+	files, err := resolveInputFiles(flag.Args())
 	if err != nil {
-		log.Fatalf("Error saving JSON: %v", err)
+		log.Fatalf("Error resolving inputs: %v", err)
+	}
+	if len(files) == 0 {
+		log.Fatalf("No .dem files found in the given inputs")
 	}
 
 	fmt.Println("========================================")
-	fmt.Printf("Conversion completed!\n")
-	fmt.Printf("Players found: %d\n", len(parser.output.Players))
-	fmt.Printf("Chat messages found: %d\n", len(parser.output.ChatMessages))
-	fmt.Printf("Combat logs found: %d\n", len(parser.output.CombatLogs))
+	fmt.Println("Go Manta DEM to JSON Converter")
+	fmt.Printf("Processing %d file(s) with %d worker(s)\n", len(files), *workers)
 	fmt.Println("========================================")
+
+	summary := RunBatch(files, cfg, *workers)
+	summary.Print()
+}
+
+func toSet(csv string) map[string]bool {
+	set := make(map[string]bool)
+	for _, item := range strings.Split(csv, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			set[item] = true
+		}
+	}
+	return set
 }
 
 func getCombatLogTypeName(logType dota.DOTA_COMBATLOG_TYPES) string {
@@ -305,4 +471,4 @@ func getGameModeName(mode int32) string {
 		return name
 	}
 	return fmt.Sprintf("UNKNOWN_%d", mode)
-}
\ No newline at end of file
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/dotabuff/manta"
+)
+
+// cellWidth is the world-units size of one Source 2 network cell; a hero's
+// true world position is cellX*cellWidth + vecX (and the same for Y/Z).
+const cellWidth = 128.0
+
+// heatmapWorldExtent is half the playable map size in world units, used to
+// size the grid in ExportHeatmapGrid. The Dota 2 map is roughly 16k units
+// across, centered on the origin.
+const heatmapWorldExtent = 8192.0
+
+// defaultPositionSampleInterval is how often (in game-time seconds) a
+// PositionTracker samples hero positions unless told otherwise.
+const defaultPositionSampleInterval = 1.0
+
+// PositionSample is one hero's location at a point in game time.
+type PositionSample struct {
+	PlayerID int32   `json:"playerId"`
+	GameTime float32 `json:"gameTime"`
+	X        float32 `json:"x"`
+	Y        float32 `json:"y"`
+}
+
+// PositionData is the Positions section of DemOutput: a flat sample list plus
+// the same samples split out per player for consumers that want a path.
+type PositionData struct {
+	Samples       []PositionSample           `json:"samples"`
+	PathsByPlayer map[int32][]PositionSample `json:"pathsByPlayer,omitempty"`
+}
+
+// PositionTracker samples hero entity positions at a fixed game-time
+// interval. It's opt-in (via --positions) since it adds a FilterEntity scan
+// to every PacketEntities callback.
+type PositionTracker struct {
+	interval       float32
+	lastSampleTime map[int32]float32
+	samples        map[int32][]PositionSample
+}
+
+func NewPositionTracker(interval float32) *PositionTracker {
+	if interval <= 0 {
+		interval = defaultPositionSampleInterval
+	}
+	return &PositionTracker{
+		interval:       interval,
+		lastSampleTime: make(map[int32]float32),
+		samples:        make(map[int32][]PositionSample),
+	}
+}
+
+// Process samples every hero whose player is known and due for another
+// sample at gameTime. playerMap comes from DemParser so the tracker can
+// resolve a player's current hero entity by class name.
+func (t *PositionTracker) Process(p *manta.Parser, playerMap map[int32]*Player, gameTime float32) {
+	heroEntities := p.FilterEntity(func(e *manta.Entity) bool {
+		return e != nil && strings.HasPrefix(e.GetClassName(), "CDOTA_Unit_Hero_")
+	})
+	entityByClass := make(map[string]*manta.Entity, len(heroEntities))
+	for _, e := range heroEntities {
+		entityByClass[e.GetClassName()] = e
+	}
+
+	for playerID, player := range playerMap {
+		if player.HeroName == "" || player.HeroName == "Unknown" {
+			continue
+		}
+		if gameTime-t.lastSampleTime[playerID] < t.interval {
+			continue
+		}
+		entity, ok := entityByClass["CDOTA_Unit_Hero_"+player.HeroName]
+		if !ok {
+			continue
+		}
+		x, y, ok := resolveWorldPosition(entity)
+		if !ok {
+			continue
+		}
+
+		t.lastSampleTime[playerID] = gameTime
+		sample := PositionSample{PlayerID: playerID, GameTime: gameTime, X: x, Y: y}
+		t.samples[playerID] = append(t.samples[playerID], sample)
+	}
+}
+
+func resolveWorldPosition(e *manta.Entity) (x, y float32, ok bool) {
+	cellX, okCX := e.GetInt32("CBodyComponent.m_cellX")
+	cellY, okCY := e.GetInt32("CBodyComponent.m_cellY")
+	vecX, okVX := e.GetFloat32("CBodyComponent.m_vecX")
+	vecY, okVY := e.GetFloat32("CBodyComponent.m_vecY")
+	if !okCX || !okCY || !okVX || !okVY {
+		return 0, 0, false
+	}
+	return float32(cellX)*cellWidth + vecX, float32(cellY)*cellWidth + vecY, true
+}
+
+// Data flattens the accumulated samples into the DemOutput.Positions shape.
+func (t *PositionTracker) Data() *PositionData {
+	data := &PositionData{PathsByPlayer: make(map[int32][]PositionSample, len(t.samples))}
+	for playerID, samples := range t.samples {
+		data.PathsByPlayer[playerID] = samples
+		data.Samples = append(data.Samples, samples...)
+	}
+	return data
+}
+
+// ExportHeatmapGrid bins every tracked player's samples into a 2D histogram
+// over a grid of cellSize x cellSize world-unit cells spanning the playable
+// map, for downstream
+// ward-placement/movement heatmaps.
+func (t *PositionTracker) ExportHeatmapGrid(cellSize float32) map[int32][][]uint32 {
+	dim := int(heatmapWorldExtent*2/cellSize) + 1
+	grids := make(map[int32][][]uint32, len(t.samples))
+
+	for playerID, samples := range t.samples {
+		grid := make([][]uint32, dim)
+		for i := range grid {
+			grid[i] = make([]uint32, dim)
+		}
+		for _, s := range samples {
+			gx := int((s.X + heatmapWorldExtent) / cellSize)
+			gy := int((s.Y + heatmapWorldExtent) / cellSize)
+			if gx < 0 || gx >= dim || gy < 0 || gy >= dim {
+				continue
+			}
+			grid[gy][gx]++
+		}
+		grids[playerID] = grid
+	}
+	return grids
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/source"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// Sink 接收解析过程中产生的记录并增量落盘，使得 GB 级别的录像也能用有界内存处理。
+// --format=json 走 bufferSink，保持原有的"解析完整场再一次性写 JSON"行为；
+// --format=ndjson/parquet 则在每个 OnC* 回调里直接写出，不再缓存到 DemOutput 的切片中。
+type Sink interface {
+	WriteCombatLog(CombatLog) error
+	WriteChatMessage(ChatMessage) error
+	WriteEntitySnapshot(EntitySnapshot) error
+	Close() error
+}
+
+// EntitySnapshot 是某个 tick 下一个被追踪实体的快照，供流式导出使用。
+type EntitySnapshot struct {
+	Tick       uint32            `json:"tick" parquet:"name=tick, type=INT64"`
+	GameTime   float32           `json:"gameTime,omitempty" parquet:"name=game_time, type=FLOAT"`
+	ClassName  string            `json:"className" parquet:"name=class_name, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Properties map[string]string `json:"properties,omitempty" parquet:"-"`
+}
+
+func newSink(outputPath, format string, split map[string]bool, output *DemOutput) (Sink, error) {
+	switch format {
+	case "json", "":
+		return &bufferSink{output: output}, nil
+	case "ndjson":
+		return newNDJSONSink(outputPath, split)
+	case "parquet":
+		return newParquetSink(outputPath, split)
+	default:
+		return nil, fmt.Errorf("unknown format %q (want json|ndjson|parquet)", format)
+	}
+}
+
+// bufferSink 复现旧行为：记录被追加到 DemOutput 的切片里，交给 SaveJSON 一次性序列化。
+type bufferSink struct {
+	output *DemOutput
+}
+
+func (b *bufferSink) WriteCombatLog(c CombatLog) error {
+	b.output.CombatLogs = append(b.output.CombatLogs, c)
+	return nil
+}
+
+func (b *bufferSink) WriteChatMessage(c ChatMessage) error {
+	b.output.ChatMessages = append(b.output.ChatMessages, c)
+	return nil
+}
+
+func (b *bufferSink) WriteEntitySnapshot(e EntitySnapshot) error {
+	b.output.EntitySnapshots = append(b.output.EntitySnapshots, e)
+	return nil
+}
+
+func (b *bufferSink) Close() error {
+	return nil
+}
+
+// ndjsonRecord 给共享文件里的每一行打上类型标签，分流文件（--split）则不需要，
+// 但为了消费者统一解析还是带上。
+type ndjsonRecord struct {
+	RecordType string      `json:"recordType"`
+	Record     interface{} `json:"record"`
+}
+
+// ndjsonSink 每种记录类型一个 *json.Encoder；--split 未覆盖的类型落到共享文件。
+type ndjsonSink struct {
+	files map[string]*os.File
+	encs  map[string]*json.Encoder
+	split map[string]bool
+}
+
+func newNDJSONSink(outputPath string, split map[string]bool) (*ndjsonSink, error) {
+	base := strings.TrimSuffix(outputPath, ".json")
+	s := &ndjsonSink{
+		files: make(map[string]*os.File),
+		encs:  make(map[string]*json.Encoder),
+		split: split,
+	}
+
+	if err := s.open("shared", base+".ndjson"); err != nil {
+		return nil, err
+	}
+	for _, recordType := range []string{"combatlog", "chat", "entities"} {
+		if split[recordType] {
+			if err := s.open(recordType, fmt.Sprintf("%s_%s.ndjson", base, recordType)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return s, nil
+}
+
+func (s *ndjsonSink) open(key, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("unable to create ndjson sink %q: %v", path, err)
+	}
+	s.files[key] = f
+	s.encs[key] = json.NewEncoder(f)
+	return nil
+}
+
+func (s *ndjsonSink) encoderFor(recordType string) *json.Encoder {
+	if s.split[recordType] {
+		return s.encs[recordType]
+	}
+	return s.encs["shared"]
+}
+
+func (s *ndjsonSink) WriteCombatLog(c CombatLog) error {
+	return s.encoderFor("combatlog").Encode(ndjsonRecord{RecordType: "combatlog", Record: c})
+}
+
+func (s *ndjsonSink) WriteChatMessage(c ChatMessage) error {
+	return s.encoderFor("chat").Encode(ndjsonRecord{RecordType: "chat", Record: c})
+}
+
+func (s *ndjsonSink) WriteEntitySnapshot(e EntitySnapshot) error {
+	return s.encoderFor("entities").Encode(ndjsonRecord{RecordType: "entities", Record: e})
+}
+
+func (s *ndjsonSink) Close() error {
+	var firstErr error
+	for _, f := range s.files {
+		if err := f.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// parquetSink writes CombatLog/ChatMessage/EntitySnapshot to separate columnar
+// files. Each record type always gets its own file regardless of --split,
+// since parquet-go needs one fixed schema per writer.
+type parquetSink struct {
+	combatLogFile   source.ParquetFile
+	combatLogWriter *writer.ParquetWriter
+
+	chatFile   source.ParquetFile
+	chatWriter *writer.ParquetWriter
+
+	entityFile   source.ParquetFile
+	entityWriter *writer.ParquetWriter
+}
+
+func newParquetSink(outputPath string, split map[string]bool) (*parquetSink, error) {
+	base := strings.TrimSuffix(outputPath, ".json")
+	s := &parquetSink{}
+
+	var err error
+	if s.combatLogFile, err = local.NewLocalFileWriter(base + "_combatlog.parquet"); err != nil {
+		return nil, fmt.Errorf("unable to open combatlog parquet file: %v", err)
+	}
+	if s.combatLogWriter, err = writer.NewParquetWriter(s.combatLogFile, new(CombatLog), 4); err != nil {
+		return nil, fmt.Errorf("unable to create combatlog parquet writer: %v", err)
+	}
+
+	if s.chatFile, err = local.NewLocalFileWriter(base + "_chat.parquet"); err != nil {
+		return nil, fmt.Errorf("unable to open chat parquet file: %v", err)
+	}
+	if s.chatWriter, err = writer.NewParquetWriter(s.chatFile, new(ChatMessage), 4); err != nil {
+		return nil, fmt.Errorf("unable to create chat parquet writer: %v", err)
+	}
+
+	if s.entityFile, err = local.NewLocalFileWriter(base + "_entities.parquet"); err != nil {
+		return nil, fmt.Errorf("unable to open entities parquet file: %v", err)
+	}
+	if s.entityWriter, err = writer.NewParquetWriter(s.entityFile, new(EntitySnapshot), 4); err != nil {
+		return nil, fmt.Errorf("unable to create entities parquet writer: %v", err)
+	}
+
+	return s, nil
+}
+
+func (s *parquetSink) WriteCombatLog(c CombatLog) error {
+	return s.combatLogWriter.Write(c)
+}
+
+func (s *parquetSink) WriteChatMessage(c ChatMessage) error {
+	return s.chatWriter.Write(c)
+}
+
+func (s *parquetSink) WriteEntitySnapshot(e EntitySnapshot) error {
+	return s.entityWriter.Write(e)
+}
+
+func (s *parquetSink) Close() error {
+	for _, w := range []*writer.ParquetWriter{s.combatLogWriter, s.chatWriter, s.entityWriter} {
+		if err := w.WriteStop(); err != nil {
+			return fmt.Errorf("error flushing parquet writer: %v", err)
+		}
+	}
+	for _, f := range []source.ParquetFile{s.combatLogFile, s.chatFile, s.entityFile} {
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
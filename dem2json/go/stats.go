@@ -0,0 +1,343 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// netWorthSampleInterval 是 NetWorthOverTime 采样间隔（游戏内秒）。
+const netWorthSampleInterval = 60
+
+// ItemPurchase 记录一次购买（时间 + 花费的金币，如果 combat log 带了 value）。
+type ItemPurchase struct {
+	ItemName string  `json:"itemName"`
+	Tick     uint32  `json:"tick"`
+	GameTime float32 `json:"gameTime"`
+	Gold     uint32  `json:"gold,omitempty"`
+}
+
+// AbilityUpgrade 记录一次技能升级。Level 需要 dota_hero_levelup 事件才能精确得知，
+// 在事件回调接入之前这里先占位，后续由 events.go 里的处理器填充。
+type AbilityUpgrade struct {
+	AbilityName string  `json:"abilityName"`
+	Level       int     `json:"level"`
+	Tick        uint32  `json:"tick"`
+	GameTime    float32 `json:"gameTime"`
+}
+
+// NetWorthSample 是每 netWorthSampleInterval 秒采一次的净值快照。
+// 目前用累计金币做近似值——combat log 里看不到装备溢价，真实净值要结合库存。
+type NetWorthSample struct {
+	GameTime float32 `json:"gameTime"`
+	NetWorth uint32  `json:"netWorth"`
+}
+
+// PlayerMatchStats 镜像 Steam GetMatchDetails 里会返回的逐玩家字段集合。
+type PlayerMatchStats struct {
+	PlayerID int32  `json:"playerId"`
+	HeroName string `json:"heroName,omitempty"`
+
+	Kills    int `json:"kills"`
+	Deaths   int `json:"deaths"`
+	Assists  int `json:"assists"`
+	Denies   int `json:"denies"`
+	LastHits int `json:"lastHits"`
+
+	GPM float32 `json:"gpm"`
+	XPM float32 `json:"xpm"`
+
+	DamageDealt uint64 `json:"damageDealt"`
+	DamageTaken uint64 `json:"damageTaken"`
+	HeroDamage  uint64 `json:"heroDamage"`
+	TowerDamage uint64 `json:"towerDamage"`
+	HealingDone uint64 `json:"healingDone"`
+
+	BuybackCount int `json:"buybackCount"`
+
+	Items              []ItemPurchase   `json:"items,omitempty"`
+	AbilityUpgrades    []AbilityUpgrade `json:"abilityUpgrades,omitempty"`
+	AbilityUsageCounts map[string]int   `json:"abilityUsageCounts,omitempty"`
+	NetWorthOverTime   []NetWorthSample `json:"netWorthOverTime,omitempty"`
+
+	totalGold        uint32
+	totalXP          uint32
+	lastNetWorthTime float32
+}
+
+// assistWindow is how far back before a hero death we still credit other
+// heroes who damaged the victim as having assisted, mirroring the kind of
+// recent-damage window the in-game assist credit is based on.
+const assistWindow = 6 // game-time seconds
+
+// damageContribution is one hero's damage against a victim, kept just long
+// enough to resolve assists when the victim dies.
+type damageContribution struct {
+	playerID int32
+	gameTime float32
+}
+
+// PlayerStatsTracker 消费每一条 combat log，增量维护逐玩家统计。
+// 它需要知道 combat log 里的英雄名字符串对应哪个 playerID，这由 RegisterPlayer 维护。
+type PlayerStatsTracker struct {
+	byPlayer map[int32]*PlayerMatchStats
+	byHero   map[string]int32 // "npc_dota_hero_x" -> playerID，用于把 SourceName/TargetName 归属到玩家
+
+	// recentDamage 按受害者名字记录最近对其造成伤害的英雄，用于在英雄死亡时回溯分配助攻。
+	recentDamage map[string][]damageContribution
+}
+
+func NewPlayerStatsTracker() *PlayerStatsTracker {
+	return &PlayerStatsTracker{
+		byPlayer:     make(map[int32]*PlayerMatchStats),
+		byHero:       make(map[string]int32),
+		recentDamage: make(map[string][]damageContribution),
+	}
+}
+
+// RegisterPlayer 在玩家英雄确定后调用，建立 combat log 名字到 playerID 的映射。
+func (t *PlayerStatsTracker) RegisterPlayer(playerID int32, heroName string) {
+	if _, ok := t.byPlayer[playerID]; !ok {
+		t.byPlayer[playerID] = &PlayerMatchStats{
+			PlayerID:           playerID,
+			HeroName:           heroName,
+			AbilityUsageCounts: make(map[string]int),
+		}
+	} else {
+		t.byPlayer[playerID].HeroName = heroName
+	}
+	t.byHero[heroCombatLogKey(heroName)] = playerID
+}
+
+func heroCombatLogKey(heroName string) string {
+	return "npc_dota_hero_" + strings.ToLower(heroName)
+}
+
+// isLastHittableCreep reports whether targetName is something Dota itself
+// counts as a last hit: lane/neutral/siege creeps. Wards, the courier, and
+// buildings (towers, barracks, Roshan) die the same way in combat log terms
+// but aren't last hits, so they're deliberately excluded here.
+func isLastHittableCreep(targetName string) bool {
+	name := strings.ToLower(targetName)
+	return strings.Contains(name, "creep") || strings.Contains(name, "neutral")
+}
+
+func (t *PlayerStatsTracker) resolve(name string) (*PlayerMatchStats, bool) {
+	playerID, ok := t.byHero[strings.ToLower(name)]
+	if !ok {
+		return nil, false
+	}
+	return t.byPlayer[playerID], true
+}
+
+// Process 把一条 combat log 记录归并进对应玩家的统计里。在英雄映射建立之前到达的
+// 记录（比如加载阶段的事件）无法归属，会被跳过——这是已知的局限。
+//
+// entry.Type comes straight from dota.DOTA_COMBATLOG_TYPES_name (see
+// getCombatLogTypeName in main.go), so DAMAGE/HEAL/DEATH/GOLD/XP/PURCHASE are
+// solid. DENY in particular is unconfirmed: dump_api.go's own hand-enumerated
+// DOTA_COMBATLOG_* constants don't list a dedicated deny value, which
+// suggests Valve may actually surface denies as a DOTA_COMBATLOG_DEATH entry
+// rather than their own type — that needs checking against a real replay.
+func (t *PlayerStatsTracker) Process(entry CombatLog) {
+	source, hasSource := t.resolve(entry.SourceName)
+	target, hasTarget := t.resolve(entry.TargetName)
+
+	switch entry.Type {
+	case "DOTA_COMBATLOG_DAMAGE":
+		if hasTarget {
+			target.DamageTaken += uint64(entry.Value)
+		}
+		if hasSource {
+			source.DamageDealt += uint64(entry.Value)
+			if hasTarget {
+				source.HeroDamage += uint64(entry.Value)
+				t.recordDamageForAssist(entry.TargetName, source.PlayerID, entry.GameTime)
+			} else if strings.Contains(strings.ToLower(entry.TargetName), "tower") {
+				source.TowerDamage += uint64(entry.Value)
+			}
+		}
+	case "DOTA_COMBATLOG_HEAL":
+		if hasSource {
+			source.HealingDone += uint64(entry.Value)
+		}
+	case "DOTA_COMBATLOG_DEATH":
+		if hasTarget {
+			target.Deaths++
+			t.creditAssists(entry.TargetName, target, source, entry.GameTime)
+			if hasSource && source != target {
+				source.Kills++
+			}
+		} else if hasSource && isLastHittableCreep(entry.TargetName) {
+			source.LastHits++
+		}
+	case "DOTA_COMBATLOG_GOLD":
+		if hasSource {
+			source.totalGold += entry.Value
+			t.maybeSampleNetWorth(source, entry.GameTime)
+		}
+	case "DOTA_COMBATLOG_XP":
+		if hasSource {
+			source.totalXP += entry.Value
+		}
+	case "DOTA_COMBATLOG_PURCHASE":
+		if hasSource {
+			source.Items = append(source.Items, ItemPurchase{
+				ItemName: entry.TargetName,
+				Tick:     entry.Tick,
+				GameTime: entry.GameTime,
+				Gold:     entry.Value,
+			})
+		}
+	case "DOTA_COMBATLOG_BUYBACK":
+		if hasSource {
+			source.BuybackCount++
+		}
+	case "DOTA_COMBATLOG_ABILITY":
+		if hasSource {
+			source.AbilityUsageCounts[entry.TargetName]++
+		}
+	case "DOTA_COMBATLOG_DENY":
+		if hasSource {
+			source.Denies++
+		}
+	}
+}
+
+// recordDamageForAssist remembers that playerID recently damaged the hero
+// named victimName, so it can be credited with an assist if that hero dies
+// shortly after. Entries older than assistWindow are pruned opportunistically
+// on each call rather than on a timer, since Process already runs once per
+// combat log line.
+func (t *PlayerStatsTracker) recordDamageForAssist(victimName string, playerID int32, gameTime float32) {
+	key := strings.ToLower(victimName)
+	contributions := t.recentDamage[key]
+
+	kept := contributions[:0]
+	for _, c := range contributions {
+		if c.playerID != playerID && gameTime-c.gameTime <= assistWindow {
+			kept = append(kept, c)
+		}
+	}
+	t.recentDamage[key] = append(kept, damageContribution{playerID: playerID, gameTime: gameTime})
+}
+
+// creditAssists awards an assist to every hero that damaged the victim within
+// assistWindow of its death, other than the killer and the victim itself
+// (self-damage shouldn't earn a dead hero an assist on their own death), then
+// clears the victim's contribution history.
+func (t *PlayerStatsTracker) creditAssists(victimName string, victim, killer *PlayerMatchStats, deathTime float32) {
+	key := strings.ToLower(victimName)
+	defer delete(t.recentDamage, key)
+
+	for _, c := range t.recentDamage[key] {
+		if deathTime-c.gameTime > assistWindow {
+			continue
+		}
+		if killer != nil && c.playerID == killer.PlayerID {
+			continue
+		}
+		if victim != nil && c.playerID == victim.PlayerID {
+			continue
+		}
+		if p, ok := t.byPlayer[c.playerID]; ok {
+			p.Assists++
+		}
+	}
+}
+
+// OnHeroLevelUp records a level-up from the dota_hero_levelup game event.
+// AbilityName is left blank here since the event only carries the new level;
+// callers that need the ability upgraded should cross-reference the ability
+// usage counts gathered from the combat log.
+func (t *PlayerStatsTracker) OnHeroLevelUp(playerID int32, level int32, tick uint32, gameTime float32) {
+	p, ok := t.byPlayer[playerID]
+	if !ok {
+		return
+	}
+	p.AbilityUpgrades = append(p.AbilityUpgrades, AbilityUpgrade{
+		Level:    int(level),
+		Tick:     tick,
+		GameTime: gameTime,
+	})
+}
+
+func (t *PlayerStatsTracker) maybeSampleNetWorth(p *PlayerMatchStats, gameTime float32) {
+	if gameTime-p.lastNetWorthTime < netWorthSampleInterval {
+		return
+	}
+	p.lastNetWorthTime = gameTime
+	p.NetWorthOverTime = append(p.NetWorthOverTime, NetWorthSample{
+		GameTime: gameTime,
+		NetWorth: p.totalGold,
+	})
+}
+
+// Finalize computes GPM/XPM from the accumulated totals and returns the
+// per-player stats in a stable (playerID-ascending) order for JSON output.
+func (t *PlayerStatsTracker) Finalize(matchDuration float32) []*PlayerMatchStats {
+	minutes := matchDuration / 60
+	if minutes <= 0 {
+		minutes = 1
+	}
+
+	result := make([]*PlayerMatchStats, 0, len(t.byPlayer))
+	for _, p := range t.byPlayer {
+		p.GPM = float32(p.totalGold) / minutes
+		p.XPM = float32(p.totalXP) / minutes
+		result = append(result, p)
+	}
+	sortPlayerStats(result)
+	return result
+}
+
+func sortPlayerStats(stats []*PlayerMatchStats) {
+	for i := 1; i < len(stats); i++ {
+		for j := i; j > 0 && stats[j].PlayerID < stats[j-1].PlayerID; j-- {
+			stats[j], stats[j-1] = stats[j-1], stats[j]
+		}
+	}
+}
+
+// ExpectedCombatLogTotals holds known-good baselines for a replay, used to spot
+// check that the combat log taxonomy above is actually catching everything.
+type ExpectedCombatLogTotals struct {
+	MatchID         uint64
+	ExpectedDamage  uint64
+	ExpectedHealing uint64
+	ExpectedDeaths  int
+}
+
+// ExpectedBaselineMatch1734886116 are the reference totals quoted against replay
+// 1734886116 (1,048,805 damage / 25,089 healing / 1,447 deaths).
+var ExpectedBaselineMatch1734886116 = ExpectedCombatLogTotals{
+	MatchID:         1734886116,
+	ExpectedDamage:  1048805,
+	ExpectedHealing: 25089,
+	ExpectedDeaths:  1447,
+}
+
+// ValidateCombatLogTotals compares aggregated totals across all players against
+// a known baseline and returns a human-readable mismatch for each field that's
+// off, or nil if everything lines up. Meant to be run by hand against a
+// reference replay rather than wired into every parse.
+func ValidateCombatLogTotals(players []*PlayerMatchStats, expected ExpectedCombatLogTotals) []string {
+	var totalDamage, totalHealing uint64
+	var totalDeaths int
+	for _, p := range players {
+		totalDamage += p.DamageDealt
+		totalHealing += p.HealingDone
+		totalDeaths += p.Deaths
+	}
+
+	var mismatches []string
+	if totalDamage != expected.ExpectedDamage {
+		mismatches = append(mismatches, fmt.Sprintf("damage: got %d, want %d", totalDamage, expected.ExpectedDamage))
+	}
+	if totalHealing != expected.ExpectedHealing {
+		mismatches = append(mismatches, fmt.Sprintf("healing: got %d, want %d", totalHealing, expected.ExpectedHealing))
+	}
+	if totalDeaths != expected.ExpectedDeaths {
+		mismatches = append(mismatches, fmt.Sprintf("deaths: got %d, want %d", totalDeaths, expected.ExpectedDeaths))
+	}
+	return mismatches
+}